@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// clusterFlag controls whether branch bodies (If/Switch/For) and function
+// bodies are wrapped in DOT "subgraph cluster_..." blocks. Graphviz draws a
+// visible boundary around a cluster, which is usually what you want, but
+// some consumers of the DOT output prefer one flat graph.
+var clusterFlag = flag.Bool("cluster", true, "group branches and functions into DOT clusters (disable for a flat graph)")
+
+// DotRenderer renders a Stmt tree as Graphviz DOT.
+type DotRenderer struct{}
+
+func (DotRenderer) Render(w io.Writer, stmts []Stmt) error {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	bw.WriteString("digraph G {\n")
+	bw.WriteString("  END [shape=doublecircle label=\"end\"]\n")
+	if _, _, err := printGraphStmts(bw, 0, stmts, graphCtx{end: "END", tail: "END"}); err != nil {
+		return err
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// PrintGraph renders stmts as DOT to w. Kept as a convenience wrapper
+// around DotRenderer for callers that only ever want Graphviz output.
+func PrintGraph(w io.Writer, stmts []Stmt) error {
+	return DotRenderer{}.Render(w, stmts)
+}
+
+// RenderProgram renders every function as its own subgraph cluster (unless
+// -cluster=false, which leaves the graph flat), with
+// Call statements wired to the target function's entry node and a return
+// edge from that function's exit back to the call site's successor.
+func (DotRenderer) RenderProgram(w io.Writer, funcs []Func) error {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	syms := symbolTable(funcs)
+	bw.WriteString("digraph G {\n")
+	for _, fd := range funcs {
+		nodes := syms[fd.Name]
+		if *clusterFlag {
+			fmt.Fprintf(bw, "  subgraph cluster_%s {\n", fd.Name)
+			fmt.Fprintf(bw, "    label=%q\n", fd.Name)
+		}
+		fmt.Fprintf(bw, "    %s [shape=point label=\"\"]\n", nodes.entry)
+		fmt.Fprintf(bw, "    %s [shape=doublecircle label=\"exit\"]\n", nodes.exit)
+		ctx := graphCtx{end: nodes.exit, tail: nodes.exit, syms: syms}
+		entry, exit, err := printGraphStmts(bw, 1, fd.Stmts, ctx)
+		if err != nil {
+			return err
+		}
+		if entry != "" {
+			fmt.Fprintf(bw, "    %s -> %s\n", nodes.entry, entry)
+		} else {
+			fmt.Fprintf(bw, "    %s -> %s\n", nodes.entry, nodes.exit)
+		}
+		if exit != "" {
+			fmt.Fprintf(bw, "    %s -> %s\n", exit, nodes.exit)
+		}
+		if *clusterFlag {
+			bw.WriteString("  }\n")
+		}
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// printGraphStmts renders stmts to w and reports the entry node (the first
+// node reached on entering this block) and the exit node (the node whose
+// successor edge is still open, i.e. where a caller should attach whatever
+// comes next). exit is "" if every path through stmts was cut short by a
+// break/continue/return, meaning there is nothing left to attach to, or if
+// stmts ends in a Call, whose return edge is already wired to ctx.tail.
+//
+// Branch bodies (If/Switch/For) recurse into the same *bufio.Writer that
+// opened their enclosing cluster, not the raw io.Writer passed in here — a
+// nested bufio.Writer wrapping w would flush straight through to the
+// underlying stream ahead of w's own buffered "subgraph cluster_... {"
+// line, splitting the cluster's braces from the nodes they're meant to
+// contain.
+func printGraphStmts(w io.Writer, level int, stmts []Stmt, ctx graphCtx) (entry, exit string, err error) {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	var prev string
+	prefix := strings.Repeat("  ", level)
+	for i, stmt := range stmts {
+		name := nextNodeID()
+		if i == 0 {
+			entry = name
+		}
+		switch x := stmt.(type) {
+		case Do:
+			fmt.Fprintf(bw, "%s  %s [shape=box label=%q]\n", prefix, name, escDot(x.What))
+			if prev != "" {
+				fmt.Fprintf(bw, "%s  %s -> %s\n", prefix, prev, name)
+			}
+			prev, exit = name, name
+
+		case If:
+			fmt.Fprintf(bw, "%s  %s [shape=diamond label=%q]\n", prefix, name, escDot(x.Cond))
+			if prev != "" {
+				fmt.Fprintf(bw, "%s  %s -> %s\n", prefix, prev, name)
+			}
+			mergeName := name + "_merge"
+			var feeds []string
+			callsMerge := false
+			for _, br := range []struct {
+				label string
+				xs    []Stmt
+			}{{"igen", x.Then}, {"nem", x.Else}} {
+				if len(br.xs) == 0 {
+					// No explicit branch: control falls straight through to
+					// whatever follows the If.
+					feeds = append(feeds, name)
+					continue
+				}
+				bodyCtx := ctx
+				bodyCtx.tail = mergeName
+				subEntry, subExit, err := printGraphBlock(bw, level, name+"_"+br.label, br.xs, bodyCtx)
+				if err != nil {
+					return "", "", err
+				}
+				fmt.Fprintf(bw, "%s  %s -> %s [label=%s]\n", prefix, name, subEntry, br.label)
+				if subExit != "" {
+					feeds = append(feeds, subExit)
+				} else if _, ok := br.xs[len(br.xs)-1].(Call); ok {
+					// A trailing Call's return edge already targets mergeName.
+					callsMerge = true
+				}
+			}
+			if len(feeds) == 0 && !callsMerge {
+				exit = ""
+			} else {
+				fmt.Fprintf(bw, "%s  %s [shape=point label=\"\"]\n", prefix, mergeName)
+				for _, f := range feeds {
+					fmt.Fprintf(bw, "%s  %s -> %s\n", prefix, f, mergeName)
+				}
+				exit = mergeName
+			}
+			prev = exit
+
+		case For:
+			fmt.Fprintf(bw, "%s  %s [shape=diamond label=%q]\n", prefix, name, escDot(x.Cond))
+			if prev != "" {
+				fmt.Fprintf(bw, "%s  %s -> %s\n", prefix, prev, name)
+			}
+			loopExit := name + "_exit"
+			if len(x.Body) != 0 {
+				bodyCtx := graphCtx{loopHead: name, loopExit: loopExit, end: ctx.end, tail: name, syms: ctx.syms}
+				subEntry, subExit, err := printGraphBlock(bw, level, name+"_body", x.Body, bodyCtx)
+				if err != nil {
+					return "", "", err
+				}
+				fmt.Fprintf(bw, "%s  %s -> %s [label=body]\n", prefix, name, subEntry)
+				if subExit != "" {
+					fmt.Fprintf(bw, "%s  %s -> %s [label=loop]\n", prefix, subExit, name)
+				}
+			}
+			fmt.Fprintf(bw, "%s  %s [shape=point label=\"\"]\n", prefix, loopExit)
+			fmt.Fprintf(bw, "%s  %s -> %s [label=done]\n", prefix, name, loopExit)
+			exit = loopExit
+			prev = exit
+
+		case Switch:
+			fmt.Fprintf(bw, "%s  %s [shape=diamond label=%q]\n", prefix, name, escDot(x.Cond))
+			if prev != "" {
+				fmt.Fprintf(bw, "%s  %s -> %s\n", prefix, prev, name)
+			}
+			mergeName := name + "_merge"
+			var feeds []string
+			callsMerge := false
+			for ci, c := range x.Cases {
+				if len(c.Body) == 0 {
+					// No explicit case body: control falls straight through
+					// to whatever follows the Switch, same as If's empty
+					// branch.
+					feeds = append(feeds, name)
+					continue
+				}
+				label := "default"
+				if !c.Default {
+					label = escDot(c.Label)
+				}
+				caseCtx := ctx
+				caseCtx.tail = mergeName
+				subEntry, subExit, err := printGraphBlock(bw, level, fmt.Sprintf("%s_case%d", name, ci), c.Body, caseCtx)
+				if err != nil {
+					return "", "", err
+				}
+				fmt.Fprintf(bw, "%s  %s -> %s [label=%q]\n", prefix, name, subEntry, label)
+				if subExit != "" {
+					feeds = append(feeds, subExit)
+				} else if _, ok := c.Body[len(c.Body)-1].(Call); ok {
+					// A trailing Call's return edge already targets mergeName.
+					callsMerge = true
+				}
+			}
+			if len(feeds) == 0 && !callsMerge {
+				exit = ""
+			} else {
+				fmt.Fprintf(bw, "%s  %s [shape=point label=\"\"]\n", prefix, mergeName)
+				for _, f := range feeds {
+					fmt.Fprintf(bw, "%s  %s -> %s\n", prefix, f, mergeName)
+				}
+				exit = mergeName
+			}
+			prev = exit
+
+		case Break:
+			fmt.Fprintf(bw, "%s  %s [shape=box peripheries=2 label=\"break\"]\n", prefix, name)
+			if prev != "" {
+				fmt.Fprintf(bw, "%s  %s -> %s\n", prefix, prev, name)
+			}
+			if ctx.loopExit != "" {
+				fmt.Fprintf(bw, "%s  %s -> %s [label=break]\n", prefix, name, ctx.loopExit)
+			}
+			prev, exit = "", ""
+
+		case Continue:
+			fmt.Fprintf(bw, "%s  %s [shape=box peripheries=2 label=\"continue\"]\n", prefix, name)
+			if prev != "" {
+				fmt.Fprintf(bw, "%s  %s -> %s\n", prefix, prev, name)
+			}
+			if ctx.loopHead != "" {
+				fmt.Fprintf(bw, "%s  %s -> %s [label=continue]\n", prefix, name, ctx.loopHead)
+			}
+			prev, exit = "", ""
+
+		case Return:
+			label := "return"
+			if x.Value != "" {
+				label = "return " + x.Value
+			}
+			fmt.Fprintf(bw, "%s  %s [shape=box peripheries=2 label=%q]\n", prefix, name, escDot(label))
+			if prev != "" {
+				fmt.Fprintf(bw, "%s  %s -> %s\n", prefix, prev, name)
+			}
+			if ctx.end != "" {
+				fmt.Fprintf(bw, "%s  %s -> %s [label=return]\n", prefix, name, ctx.end)
+			}
+			prev, exit = "", ""
+
+		case Call:
+			target := resolveCall(ctx.syms, x.Name)
+			fmt.Fprintf(bw, "%s  %s [shape=box style=dashed label=%q]\n", prefix, name, escDot("call "+x.Name))
+			if prev != "" {
+				fmt.Fprintf(bw, "%s  %s -> %s\n", prefix, prev, name)
+			}
+			fmt.Fprintf(bw, "%s  %s -> %s [label=call]\n", prefix, name, target.entry)
+			succ := ctx.tail
+			if i < len(stmts)-1 {
+				succ = peekNextNodeID()
+			}
+			if succ != "" {
+				fmt.Fprintf(bw, "%s  %s -> %s [label=return]\n", prefix, target.exit, succ)
+			}
+			// The return edge above already wires the call's successor;
+			// chaining prev/exit the way Do does would additionally wire
+			// the call box straight to its successor, bypassing the call
+			// entirely.
+			prev, exit = "", ""
+
+		default:
+			return "", "", errors.Errorf("unknown Stmt %T %+v", x, x)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return "", "", err
+	}
+	return entry, exit, nil
+}
+
+// printGraphBlock renders a branch body (an If/Switch/For's child
+// statement list), optionally wrapping it in a named DOT cluster so
+// Graphviz draws a boundary around it. bw must be the *bufio.Writer that
+// opened the enclosing block, so the cluster's braces and its child nodes
+// land in the stream in the right order.
+func printGraphBlock(bw *bufio.Writer, level int, clusterName string, stmts []Stmt, ctx graphCtx) (entry, exit string, err error) {
+	prefix := strings.Repeat("  ", level)
+	if *clusterFlag {
+		fmt.Fprintf(bw, "%s  subgraph cluster_%s {\n", prefix, clusterName)
+	}
+	entry, exit, err = printGraphStmts(bw, level+1, stmts, ctx)
+	if err != nil {
+		return "", "", err
+	}
+	if *clusterFlag {
+		fmt.Fprintf(bw, "%s  }\n", prefix)
+	}
+	return entry, exit, nil
+}
+
+// escDot prepares a label for use inside a DOT %q-quoted attribute value,
+// HTML-escaping it since DOT labels are otherwise interpreted as HTML-like
+// strings when they contain angle brackets.
+func escDot(s string) string {
+	if s == "" {
+		return s
+	}
+	return html.EscapeString(prepLabel(s))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestDotCallNoDoubleEdge verifies that a Call site wires exactly one edge
+// into its successor (the callee's return edge), not a second bogus edge
+// straight from the call box.
+func TestDotCallNoDoubleEdge(t *testing.T) {
+	resetNodeCounter()
+	funcs := []Func{
+		{Name: "caller", Stmts: []Stmt{Do{What: "start"}, Call{Name: "helper"}, Do{What: "end"}}},
+		{Name: "helper", Stmts: []Stmt{Do{What: "helping"}}},
+	}
+	var buf bytes.Buffer
+	if err := (DotRenderer{}).RenderProgram(&buf, funcs); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "F_helper_exit -> N003 [label=return]") {
+		t.Errorf("missing callee return edge into successor:\n%s", out)
+	}
+	if strings.Contains(out, "N002 -> N003\n") {
+		t.Errorf("call box should not also wire straight to its successor, got:\n%s", out)
+	}
+}
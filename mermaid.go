@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MermaidRenderer renders a Stmt tree as a Mermaid flowchart
+// (https://mermaid.js.org/syntax/flowchart.html).
+type MermaidRenderer struct{}
+
+func (MermaidRenderer) Render(w io.Writer, stmts []Stmt) error {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	bw.WriteString("flowchart TD\n")
+	bw.WriteString("  END((end))\n")
+	if _, err := mermaidStmts(bw, stmts, graphCtx{end: "END", tail: "END"}); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// mermaidStmts mirrors printGraphStmts: it renders stmts and returns the
+// name of the last node whose successor edge is still open, or "" if the
+// chain was cut short by a break/continue/return or ends in a Call, whose
+// return edge is already wired to ctx.tail.
+func mermaidStmts(w io.Writer, stmts []Stmt, ctx graphCtx) (string, error) {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	var prev string
+	for i, stmt := range stmts {
+		name := nextNodeID()
+		switch x := stmt.(type) {
+		case Do:
+			fmt.Fprintf(bw, "  %s[%q]\n", name, escMermaid(x.What))
+			if prev != "" {
+				fmt.Fprintf(bw, "  %s --> %s\n", prev, name)
+			}
+			prev = name
+
+		case If:
+			fmt.Fprintf(bw, "  %s{%q}\n", name, escMermaid(x.Cond))
+			if prev != "" {
+				fmt.Fprintf(bw, "  %s --> %s\n", prev, name)
+			}
+			mergeName := name + "_merge"
+			var feeds []string
+			callsMerge := false
+			for _, br := range []struct {
+				label string
+				xs    []Stmt
+			}{{"yes", x.Then}, {"no", x.Else}} {
+				if len(br.xs) == 0 {
+					// No explicit branch: control falls straight through to
+					// whatever follows the If.
+					feeds = append(feeds, name)
+					continue
+				}
+				nxt := peekNextNodeID()
+				fmt.Fprintf(bw, "  %s -->|%s| %s\n", name, br.label, nxt)
+				branchCtx := ctx
+				branchCtx.tail = mergeName
+				last, err := mermaidStmts(w, br.xs, branchCtx)
+				if err != nil {
+					return "", err
+				}
+				if last != "" {
+					feeds = append(feeds, last)
+				} else if _, ok := br.xs[len(br.xs)-1].(Call); ok {
+					// A trailing Call's return edge already targets mergeName.
+					callsMerge = true
+				}
+			}
+			if len(feeds) == 0 && !callsMerge {
+				prev = ""
+			} else {
+				fmt.Fprintf(bw, "  %s((merge))\n", mergeName)
+				for _, f := range feeds {
+					fmt.Fprintf(bw, "  %s --> %s\n", f, mergeName)
+				}
+				prev = mergeName
+			}
+
+		case For:
+			fmt.Fprintf(bw, "  %s{%q}\n", name, escMermaid(x.Cond))
+			if prev != "" {
+				fmt.Fprintf(bw, "  %s --> %s\n", prev, name)
+			}
+			loopExit := name + "_exit"
+			if len(x.Body) != 0 {
+				nxt := peekNextNodeID()
+				fmt.Fprintf(bw, "  %s -->|body| %s\n", name, nxt)
+				last, err := mermaidStmts(w, x.Body, graphCtx{loopHead: name, loopExit: loopExit, end: ctx.end, tail: name})
+				if err != nil {
+					return "", err
+				}
+				if last != "" {
+					fmt.Fprintf(bw, "  %s -->|loop| %s\n", last, name)
+				}
+			}
+			fmt.Fprintf(bw, "  %s((done))\n", loopExit)
+			fmt.Fprintf(bw, "  %s -->|done| %s\n", name, loopExit)
+			prev = loopExit
+
+		case Switch:
+			fmt.Fprintf(bw, "  %s{%q}\n", name, escMermaid(x.Cond))
+			if prev != "" {
+				fmt.Fprintf(bw, "  %s --> %s\n", prev, name)
+			}
+			mergeName := name + "_merge"
+			var feeds []string
+			callsMerge := false
+			for _, c := range x.Cases {
+				label := "default"
+				if !c.Default {
+					label = escMermaid(c.Label)
+				}
+				if len(c.Body) == 0 {
+					// No explicit case body: control falls straight through
+					// to the merge point, same as If's empty branch — but
+					// still draw the label so the case isn't silently
+					// dropped from the diagram.
+					fmt.Fprintf(bw, "  %s -->|%s| %s\n", name, label, mergeName)
+					feeds = append(feeds, name)
+					continue
+				}
+				nxt := peekNextNodeID()
+				fmt.Fprintf(bw, "  %s -->|%s| %s\n", name, label, nxt)
+				caseCtx := ctx
+				caseCtx.tail = mergeName
+				last, err := mermaidStmts(w, c.Body, caseCtx)
+				if err != nil {
+					return "", err
+				}
+				if last != "" {
+					feeds = append(feeds, last)
+				} else if _, ok := c.Body[len(c.Body)-1].(Call); ok {
+					// A trailing Call's return edge already targets mergeName.
+					callsMerge = true
+				}
+			}
+			if len(feeds) == 0 && !callsMerge {
+				prev = ""
+			} else {
+				fmt.Fprintf(bw, "  %s((merge))\n", mergeName)
+				for _, f := range feeds {
+					if f == name {
+						// Already drawn above with its case label.
+						continue
+					}
+					fmt.Fprintf(bw, "  %s --> %s\n", f, mergeName)
+				}
+				prev = mergeName
+			}
+
+		case Break:
+			fmt.Fprintf(bw, "  %s([break])\n", name)
+			if prev != "" {
+				fmt.Fprintf(bw, "  %s --> %s\n", prev, name)
+			}
+			if ctx.loopExit != "" {
+				fmt.Fprintf(bw, "  %s -->|break| %s\n", name, ctx.loopExit)
+			}
+			prev = ""
+
+		case Continue:
+			fmt.Fprintf(bw, "  %s([continue])\n", name)
+			if prev != "" {
+				fmt.Fprintf(bw, "  %s --> %s\n", prev, name)
+			}
+			if ctx.loopHead != "" {
+				fmt.Fprintf(bw, "  %s -->|continue| %s\n", name, ctx.loopHead)
+			}
+			prev = ""
+
+		case Return:
+			label := "return"
+			if x.Value != "" {
+				label = "return " + x.Value
+			}
+			fmt.Fprintf(bw, "  %s([%q])\n", name, escMermaid(label))
+			if prev != "" {
+				fmt.Fprintf(bw, "  %s --> %s\n", prev, name)
+			}
+			if ctx.end != "" {
+				fmt.Fprintf(bw, "  %s -->|return| %s\n", name, ctx.end)
+			}
+			prev = ""
+
+		case Call:
+			target := resolveCall(ctx.syms, x.Name)
+			fmt.Fprintf(bw, "  %s[%q]\n", name, escMermaid("call "+x.Name))
+			if prev != "" {
+				fmt.Fprintf(bw, "  %s --> %s\n", prev, name)
+			}
+			fmt.Fprintf(bw, "  %s -->|call| %s\n", name, target.entry)
+			succ := ctx.tail
+			if i < len(stmts)-1 {
+				succ = peekNextNodeID()
+			}
+			if succ != "" {
+				fmt.Fprintf(bw, "  %s -->|return| %s\n", target.exit, succ)
+			}
+			// The return edge above already wires the call's successor;
+			// chaining prev the way Do does would additionally wire the
+			// call box straight to its successor, bypassing the call
+			// entirely.
+			prev = ""
+
+		default:
+			return "", errors.Errorf("unknown Stmt %T %+v", x, x)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return "", err
+	}
+	return prev, nil
+}
+
+// RenderProgram renders every function as its own named Mermaid subgraph,
+// with Call statements wired to the target function's entry node and a
+// return edge from that function's exit back to the call site's successor.
+func (MermaidRenderer) RenderProgram(w io.Writer, funcs []Func) error {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	syms := symbolTable(funcs)
+	bw.WriteString("flowchart TD\n")
+	for _, fd := range funcs {
+		nodes := syms[fd.Name]
+		fmt.Fprintf(bw, "  subgraph sub_%s [%s]\n", fd.Name, fd.Name)
+		fmt.Fprintf(bw, "    %s((start))\n", nodes.entry)
+		fmt.Fprintf(bw, "    %s((exit))\n", nodes.exit)
+		ctx := graphCtx{end: nodes.exit, tail: nodes.exit, syms: syms}
+		firstName := peekNextNodeID()
+		last, err := mermaidStmts(bw, fd.Stmts, ctx)
+		if err != nil {
+			return err
+		}
+		if len(fd.Stmts) != 0 {
+			fmt.Fprintf(bw, "    %s --> %s\n", nodes.entry, firstName)
+		} else {
+			fmt.Fprintf(bw, "    %s --> %s\n", nodes.entry, nodes.exit)
+		}
+		if last != "" {
+			fmt.Fprintf(bw, "    %s --> %s\n", last, nodes.exit)
+		}
+		bw.WriteString("  end\n")
+	}
+	return bw.Flush()
+}
+
+// escMermaid prepares a label for use inside a quoted Mermaid node/edge
+// label. Mermaid labels don't accept HTML-escaped angle brackets the way
+// DOT's do, so unlike escDot this only neutralizes the characters that
+// would otherwise break Mermaid's own quoting.
+func escMermaid(s string) string {
+	if s == "" {
+		return s
+	}
+	s = prepLabel(s)
+	s = strings.ReplaceAll(s, `"`, "#quot;")
+	s = strings.ReplaceAll(s, "\n", "<br/>")
+	return s
+}
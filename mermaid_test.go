@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestMermaidIfMerge verifies that both branches of an If rejoin at a merge
+// node instead of being dead ends, and that the statement following the If
+// is only reachable through that merge node.
+func TestMermaidIfMerge(t *testing.T) {
+	resetNodeCounter()
+	stmts := []Stmt{
+		If{Cond: "cond", Then: []Stmt{Do{What: "a"}}, Else: []Stmt{Do{What: "b"}}},
+		Do{What: "after"},
+	}
+	var buf bytes.Buffer
+	if err := (MermaidRenderer{}).Render(&buf, stmts); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"N002 --> N001_merge",
+		"N003 --> N001_merge",
+		"N001_merge --> N004",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "N001 --> N004") {
+		t.Errorf("statement after If should only be reachable via the merge node, got:\n%s", out)
+	}
+}
+
+// TestMermaidCallNoDoubleEdge verifies that a Call site wires exactly one
+// edge into its successor (the callee's return edge), not a second bogus
+// edge straight from the call box.
+func TestMermaidCallNoDoubleEdge(t *testing.T) {
+	resetNodeCounter()
+	funcs := []Func{
+		{Name: "caller", Stmts: []Stmt{Do{What: "start"}, Call{Name: "helper"}, Do{What: "end"}}},
+		{Name: "helper", Stmts: []Stmt{Do{What: "helping"}}},
+	}
+	var buf bytes.Buffer
+	if err := (MermaidRenderer{}).RenderProgram(&buf, funcs); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "F_helper_exit -->|return| N003") {
+		t.Errorf("missing callee return edge into successor:\n%s", out)
+	}
+	if strings.Contains(out, "N002 --> N003") {
+		t.Errorf("call box should not also wire straight to its successor, got:\n%s", out)
+	}
+}
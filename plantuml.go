@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PlantUMLRenderer renders a Stmt tree as a PlantUML activity diagram
+// (https://plantuml.com/activity-diagram-beta).
+type PlantUMLRenderer struct{}
+
+func (PlantUMLRenderer) Render(w io.Writer, stmts []Stmt) error {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	bw.WriteString("@startuml\n")
+	bw.WriteString("start\n")
+	if err := plantUMLStmts(bw, stmts, graphCtx{end: "stop"}); err != nil {
+		return err
+	}
+	bw.WriteString("stop\n")
+	bw.WriteString("@enduml\n")
+	return bw.Flush()
+}
+
+// plantUMLStmts renders stmts as PlantUML activity-diagram statements.
+// Unlike printGraphStmts/mermaidStmts it doesn't need to return the last
+// open node: PlantUML's control structures (if/while/switch) already
+// nest the flow, so a break only needs its native "break" keyword and a
+// return only needs "stop".
+func plantUMLStmts(w io.Writer, stmts []Stmt, ctx graphCtx) error {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	for _, stmt := range stmts {
+		name := nextNodeID()
+		switch x := stmt.(type) {
+		case Do:
+			fmt.Fprintf(bw, ":%s; ' %s\n", escPlantUML(x.What), name)
+
+		case If:
+			fmt.Fprintf(bw, "if (%s) then (yes) ' %s\n", escPlantUML(x.Cond), name)
+			if len(x.Then) != 0 {
+				if err := plantUMLStmts(w, x.Then, ctx); err != nil {
+					return err
+				}
+			}
+			if len(x.Else) != 0 {
+				bw.WriteString("else (no)\n")
+				if err := plantUMLStmts(w, x.Else, ctx); err != nil {
+					return err
+				}
+			}
+			bw.WriteString("endif\n")
+
+		case For:
+			fmt.Fprintf(bw, "while (%s) is (yes) ' %s\n", escPlantUML(x.Cond), name)
+			if err := plantUMLStmts(w, x.Body, graphCtx{loopHead: name, loopExit: name, end: ctx.end}); err != nil {
+				return err
+			}
+			bw.WriteString("endwhile (no)\n")
+
+		case Switch:
+			fmt.Fprintf(bw, "switch (%s) ' %s\n", escPlantUML(x.Cond), name)
+			for _, c := range x.Cases {
+				if c.Default {
+					bw.WriteString("default ()\n")
+				} else {
+					fmt.Fprintf(bw, "case ( %s )\n", escPlantUML(c.Label))
+				}
+				if err := plantUMLStmts(w, c.Body, ctx); err != nil {
+					return err
+				}
+			}
+			bw.WriteString("endswitch\n")
+
+		case Break:
+			bw.WriteString("break\n")
+
+		case Continue:
+			fmt.Fprintf(bw, ":continue; ' %s\n", name)
+
+		case Return:
+			if x.Value != "" {
+				fmt.Fprintf(bw, ":return %s;\n", escPlantUML(x.Value))
+			}
+			bw.WriteString("stop\n")
+
+		case Call:
+			// Activity diagrams have no notion of a dynamic call stack, so
+			// the call is rendered as a single action naming its target
+			// rather than an edge into another partition's entry node.
+			fmt.Fprintf(bw, ":call %s; ' %s\n", escPlantUML(x.Name), name)
+
+		default:
+			return errors.Errorf("unknown Stmt %T %+v", x, x)
+		}
+	}
+	return bw.Flush()
+}
+
+// RenderProgram renders every function as its own PlantUML partition.
+func (PlantUMLRenderer) RenderProgram(w io.Writer, funcs []Func) error {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	syms := symbolTable(funcs)
+	bw.WriteString("@startuml\n")
+	for _, fd := range funcs {
+		nodes := syms[fd.Name]
+		fmt.Fprintf(bw, "partition %s {\n", fd.Name)
+		bw.WriteString("start\n")
+		if err := plantUMLStmts(bw, fd.Stmts, graphCtx{end: nodes.exit, syms: syms}); err != nil {
+			return err
+		}
+		bw.WriteString("stop\n")
+		bw.WriteString("}\n")
+	}
+	bw.WriteString("@enduml\n")
+	return bw.Flush()
+}
+
+// escPlantUML prepares a label for use inside a PlantUML activity action
+// (":text;"). Unlike escDot this doesn't HTML-escape angle brackets —
+// PlantUML interprets those as its own inline markup — it only protects
+// the characters that would otherwise terminate the action early.
+func escPlantUML(s string) string {
+	if s == "" {
+		return s
+	}
+	s = prepLabel(s)
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	s = strings.ReplaceAll(s, ";", ",")
+	return s
+}
@@ -1,21 +1,18 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"flag"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
-	"html"
 	"io"
 	"log"
 	"os"
+	"strconv"
 	"strings"
-	"sync/atomic"
 
-	wordwrap "github.com/mitchellh/go-wordwrap"
 	"github.com/pkg/errors"
 )
 
@@ -28,6 +25,14 @@ func main() {
 func Main() error {
 	flag.Parse()
 	fn := flag.Arg(0)
+
+	if *watchFlag {
+		if fn == "" || fn == "-" {
+			return errors.New("-watch requires a file argument, not stdin")
+		}
+		return watchRender(fn)
+	}
+
 	fh := os.Stdin
 	if fn != "" && fn != "-" {
 		var err error
@@ -41,9 +46,14 @@ func Main() error {
 	if err != nil {
 		return err
 	}
-	src := buf.String()
-	buf.Reset()
+	return renderSource(fn, buf.String(), os.Stdout)
+}
 
+// renderSource parses src (the pseudo-Go source of a flowchart, read from
+// fn for error messages) and writes its commented statement dump followed
+// by the rendered diagram to w.
+func renderSource(fn, src string, w io.Writer) error {
+	resetNodeCounter()
 	r := io.Reader(strings.NewReader(src))
 	if !(strings.HasPrefix(src, "package ") || strings.Contains(src, "\npackage ")) {
 		r = io.MultiReader(
@@ -60,81 +70,33 @@ func main() {`),
 	if err != nil {
 		return errors.Wrap(err, "parse")
 	}
+	var funcs []Func
 	for _, d := range f.Decls {
 		fd, _ := d.(*ast.FuncDecl)
-		if fd == nil || fd.Name.Name != "main" {
+		if fd == nil || fd.Body == nil {
 			continue
 		}
 		stmts, err := parseStmtList(fd.Body.List)
 		if err != nil {
 			return err
 		}
-		fmt.Fprintf(os.Stdout, "/*\n")
-		if err = Print(os.Stdout, "# ", stmts); err != nil {
-			return err
-		}
-		fmt.Fprintf(os.Stdout, "*/\n")
-		if err = PrintGraph(os.Stdout, stmts); err != nil {
+		funcs = append(funcs, Func{Name: fd.Name.Name, Stmts: stmts})
+	}
+
+	fmt.Fprintf(w, "/*\n")
+	for _, fd := range funcs {
+		fmt.Fprintf(w, "# func %s\n", fd.Name)
+		if err := Print(w, "# ", fd.Stmts); err != nil {
 			return err
 		}
-		break
 	}
-	return nil
-}
+	fmt.Fprintf(w, "*/\n")
 
-func PrintGraph(w io.Writer, stmts []Stmt) error {
-	bw := bufio.NewWriter(w)
-	defer bw.Flush()
-	bw.WriteString("digraph G {\n")
-	if err := printGraphStmts(bw, 0, stmts); err != nil {
+	rd, err := rendererFor(*formatFlag)
+	if err != nil {
 		return err
 	}
-	bw.WriteString("}\n")
-	return bw.Flush()
-}
-
-var nodeCnt int32
-
-func printGraphStmts(w io.Writer, level int, stmts []Stmt) error {
-	bw := bufio.NewWriter(w)
-	defer bw.Flush()
-	var prev string
-	prefix := strings.Repeat("  ", level)
-	for _, stmt := range stmts {
-		cnt := atomic.AddInt32(&nodeCnt, 1)
-		name := fmt.Sprintf("N%03d", cnt)
-		switch x := stmt.(type) {
-		case Do:
-			fmt.Fprintf(bw, "%s  %s [shape=box label=%q]\n", prefix, name, esc(x.What))
-			if prev != "" {
-				fmt.Fprintf(bw, "%s  %s -> %s\n", prefix, prev, name)
-			}
-			prev = name
-		case If:
-			fmt.Fprintf(bw, "%s  %s [shape=diamond label=%q]\n", prefix, name, esc(x.Cond))
-			if prev != "" {
-				fmt.Fprintf(bw, "%s  %s -> %s\n", prefix, prev, name)
-			}
-			for k, xs := range map[string][]Stmt{"igen": x.Then, "nem": x.Else} {
-				if len(xs) == 0 {
-					continue
-				}
-				nxtName := fmt.Sprintf("N%03d", atomic.LoadInt32(&nodeCnt)+1)
-				fmt.Fprintf(bw, "%s  %s -> %s [label=%s]\n", prefix, name, nxtName, k)
-				subName := fmt.Sprintf("%s_%s", name, k)
-				fmt.Fprintf(bw, "%s  subgraph %s {\n", prefix, subName)
-				if err := printGraphStmts(w, level+1, xs); err != nil {
-					return err
-				}
-				fmt.Fprintf(bw, "%s  }\n", prefix)
-			}
-			prev = name
-
-		default:
-			return errors.Errorf("unknown Stmt %T %+v", x, x)
-		}
-	}
-	return bw.Flush()
+	return rd.RenderProgram(w, funcs)
 }
 
 func Print(w io.Writer, prefix string, stmts []Stmt) error {
@@ -175,17 +137,115 @@ func (i If) Print(w io.Writer, prefix string) error {
 	return Print(w, prefix+"  ", i.Else)
 }
 
+type For struct {
+	Cond string
+	Body []Stmt
+}
+
+func (f For) Print(w io.Writer, prefix string) error {
+	if _, err := fmt.Fprintf(w, "%sFOR %s DO\n", prefix, f.Cond); err != nil {
+		return err
+	}
+	return Print(w, prefix+"  ", f.Body)
+}
+
+type Case struct {
+	Label   string
+	Default bool
+	Body    []Stmt
+}
+
+type Switch struct {
+	Cond  string
+	Cases []Case
+}
+
+func (s Switch) Print(w io.Writer, prefix string) error {
+	if _, err := fmt.Fprintf(w, "%sSWITCH %s\n", prefix, s.Cond); err != nil {
+		return err
+	}
+	for _, c := range s.Cases {
+		if c.Default {
+			if _, err := fmt.Fprintf(w, "%s  DEFAULT\n", prefix); err != nil {
+				return err
+			}
+		} else if _, err := fmt.Fprintf(w, "%s  CASE %s\n", prefix, c.Label); err != nil {
+			return err
+		}
+		if err := Print(w, prefix+"    ", c.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Break struct{}
+
+func (Break) Print(w io.Writer, prefix string) error {
+	_, err := fmt.Fprintf(w, "%sBREAK\n", prefix)
+	return err
+}
+
+type Continue struct{}
+
+func (Continue) Print(w io.Writer, prefix string) error {
+	_, err := fmt.Fprintf(w, "%sCONTINUE\n", prefix)
+	return err
+}
+
+type Call struct {
+	Name string
+}
+
+func (c Call) Print(w io.Writer, prefix string) error {
+	_, err := fmt.Fprintf(w, "%sCALL %s\n", prefix, c.Name)
+	return err
+}
+
+type Return struct {
+	Value string
+}
+
+func (r Return) Print(w io.Writer, prefix string) error {
+	if r.Value == "" {
+		_, err := fmt.Fprintf(w, "%sRETURN\n", prefix)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%sRETURN %s\n", prefix, r.Value)
+	return err
+}
+
 func parseStmtList(stmtList []ast.Stmt) ([]Stmt, error) {
 	stmts := make([]Stmt, 0, len(stmtList))
 	for _, stmt := range stmtList {
 		switch x := stmt.(type) {
 		case *ast.ExprStmt:
-			if c, _ := x.X.(*ast.CallExpr); c == nil || len(c.Args) != 1 {
+			c, _ := x.X.(*ast.CallExpr)
+			if c == nil {
 				return stmts, errors.Errorf("unknown call %T %+v (wanted with one arg)", x.X, x.X)
-			} else if bl, _ := c.Args[0].(*ast.BasicLit); bl == nil || bl.Kind != token.STRING {
-				return stmts, errors.Errorf("unknown arg %T %+v (wanted string)", c.Args[0], c.Args[0])
-			} else {
+			}
+			fn, _ := c.Fun.(*ast.Ident)
+			switch {
+			case fn != nil && fn.Name == "call" && len(c.Args) == 1:
+				bl, _ := c.Args[0].(*ast.BasicLit)
+				if bl == nil || bl.Kind != token.STRING {
+					return stmts, errors.Errorf("unknown call arg %T %+v (wanted string)", c.Args[0], c.Args[0])
+				}
+				name, err := strconv.Unquote(bl.Value)
+				if err != nil {
+					return stmts, errors.Wrapf(err, "call target %s", bl.Value)
+				}
+				stmts = append(stmts, Call{Name: name})
+			case fn != nil && fn.Name != "do" && len(c.Args) == 0:
+				stmts = append(stmts, Call{Name: fn.Name})
+			case len(c.Args) == 1:
+				bl, _ := c.Args[0].(*ast.BasicLit)
+				if bl == nil || bl.Kind != token.STRING {
+					return stmts, errors.Errorf("unknown arg %T %+v (wanted string)", c.Args[0], c.Args[0])
+				}
 				stmts = append(stmts, Do{What: bl.Value})
+			default:
+				return stmts, errors.Errorf("unknown call %T %+v (wanted with one arg)", x.X, x.X)
 			}
 		case *ast.IfStmt:
 			if c, _ := x.Cond.(*ast.CallExpr); c == nil || len(c.Args) != 1 {
@@ -210,27 +270,87 @@ func parseStmtList(stmtList []ast.Stmt) ([]Stmt, error) {
 				}
 				stmts = append(stmts, ifs)
 			}
+		case *ast.ForStmt:
+			if x.Init != nil || x.Post != nil {
+				return stmts, errors.Errorf("unsupported for-loop init/post %+v", x)
+			}
+			c, _ := x.Cond.(*ast.CallExpr)
+			if c == nil || len(c.Args) != 1 {
+				return stmts, errors.Errorf("unknown for cond %T %+v (wanted with one arg)", x.Cond, x.Cond)
+			}
+			bl, _ := c.Args[0].(*ast.BasicLit)
+			if bl == nil || bl.Kind != token.STRING {
+				return stmts, errors.Errorf("unknown cond arg %T %+v (wanted string)", c.Args[0], c.Args[0])
+			}
+			fs := For{Cond: bl.Value}
+			if x.Body != nil && len(x.Body.List) != 0 {
+				var err error
+				if fs.Body, err = parseStmtList(x.Body.List); err != nil {
+					return stmts, err
+				}
+			}
+			stmts = append(stmts, fs)
+		case *ast.SwitchStmt:
+			c, _ := x.Tag.(*ast.CallExpr)
+			if c == nil || len(c.Args) != 1 {
+				return stmts, errors.Errorf("unknown switch tag %T %+v (wanted with one arg)", x.Tag, x.Tag)
+			}
+			bl, _ := c.Args[0].(*ast.BasicLit)
+			if bl == nil || bl.Kind != token.STRING {
+				return stmts, errors.Errorf("unknown tag arg %T %+v (wanted string)", c.Args[0], c.Args[0])
+			}
+			sw := Switch{Cond: bl.Value}
+			for _, cs := range x.Body.List {
+				cc, _ := cs.(*ast.CaseClause)
+				if cc == nil {
+					return stmts, errors.Errorf("unknown switch clause %T %+v", cs, cs)
+				}
+				cse := Case{Default: len(cc.List) == 0}
+				if !cse.Default {
+					if len(cc.List) != 1 {
+						return stmts, errors.Errorf("unsupported multi-value case %+v", cc.List)
+					}
+					clbl, _ := cc.List[0].(*ast.BasicLit)
+					if clbl == nil || clbl.Kind != token.STRING {
+						return stmts, errors.Errorf("unknown case label %T %+v (wanted string)", cc.List[0], cc.List[0])
+					}
+					cse.Label = clbl.Value
+				}
+				if len(cc.Body) != 0 {
+					var err error
+					if cse.Body, err = parseStmtList(cc.Body); err != nil {
+						return stmts, err
+					}
+				}
+				sw.Cases = append(sw.Cases, cse)
+			}
+			stmts = append(stmts, sw)
+		case *ast.BranchStmt:
+			switch x.Tok {
+			case token.BREAK:
+				stmts = append(stmts, Break{})
+			case token.CONTINUE:
+				stmts = append(stmts, Continue{})
+			default:
+				return stmts, errors.Errorf("unsupported branch statement %+v", x)
+			}
+		case *ast.ReturnStmt:
+			ret := Return{}
+			switch len(x.Results) {
+			case 0:
+			case 1:
+				bl, _ := x.Results[0].(*ast.BasicLit)
+				if bl == nil || bl.Kind != token.STRING {
+					return stmts, errors.Errorf("unknown return value %T %+v (wanted string)", x.Results[0], x.Results[0])
+				}
+				ret.Value = bl.Value
+			default:
+				return stmts, errors.Errorf("unsupported multi-value return %+v", x.Results)
+			}
+			stmts = append(stmts, ret)
 		default:
 			return stmts, errors.Errorf("unknown statement %T %+v", x, x)
 		}
 	}
 	return stmts, nil
 }
-
-func esc(s string) string {
-	if s == "" {
-		return s
-	}
-	if len(s) < 2 {
-		return html.EscapeString(s)
-	}
-	if !(strings.Contains(s, "\n") || strings.Contains(s, "<br>")) {
-		s = wordwrap.WrapString(s, 30)
-	}
-	//s = strings.Replace(s, "\n", "\\n", -1)
-	if s[0] == '`' && s[len(s)-1] == '`' {
-		return html.EscapeString(s[1 : len(s)-1])
-	}
-
-	return html.EscapeString(s)
-}
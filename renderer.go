@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	wordwrap "github.com/mitchellh/go-wordwrap"
+	"github.com/pkg/errors"
+)
+
+var formatFlag = flag.String("format", "dot", "output format: dot, mermaid or plantuml")
+
+// Renderer turns a parsed Stmt tree into a diagram in some textual format.
+type Renderer interface {
+	Render(w io.Writer, stmts []Stmt) error
+	RenderProgram(w io.Writer, funcs []Func) error
+}
+
+// Func pairs a top-level function's name with its parsed statement list, as
+// collected from every *ast.FuncDecl in the source file (not just "main").
+type Func struct {
+	Name  string
+	Stmts []Stmt
+}
+
+// funcNodes holds the entry/exit node names synthesized for a declared
+// function. Unlike the N### node names handed out by nextNodeID, these are
+// derived purely from the function's name, so the whole symbol table can be
+// built in a first pass before any function body is rendered.
+type funcNodes struct {
+	entry, exit string
+}
+
+// symbolTable builds the entry/exit node names for every declared function.
+// This is the "first pass" of the two-pass approach: it only needs the
+// function names, not their bodies, so Call edges can be wired to a target
+// that is declared later in the file (or even recursively to itself).
+func symbolTable(funcs []Func) map[string]funcNodes {
+	syms := make(map[string]funcNodes, len(funcs))
+	for _, fn := range funcs {
+		syms[fn.Name] = funcNodes{entry: "F_" + fn.Name + "_entry", exit: "F_" + fn.Name + "_exit"}
+	}
+	return syms
+}
+
+// rendererFor resolves the -format flag value to a Renderer.
+func rendererFor(format string) (Renderer, error) {
+	switch format {
+	case "", "dot":
+		return DotRenderer{}, nil
+	case "mermaid":
+		return MermaidRenderer{}, nil
+	case "plantuml":
+		return PlantUMLRenderer{}, nil
+	default:
+		return nil, errors.Errorf("unknown -format %q (want dot, mermaid or plantuml)", format)
+	}
+}
+
+// graphCtx carries the enclosing loop's head/exit and the function's end
+// node so that break, continue and return can route their outgoing edge
+// to the right place instead of the following sibling. tail is where the
+// *current* statement list itself routes to once it runs off its own end
+// (the enclosing If/Switch's merge node, the enclosing For's head, or the
+// function end at the top level) — a Call that is the last statement in
+// the list needs this to wire its return edge correctly, since a Call in
+// the middle of a list can just target its next sibling but a trailing
+// one can't. It is shared by every Renderer implementation.
+type graphCtx struct {
+	loopHead string
+	loopExit string
+	end      string
+	tail     string
+	syms     map[string]funcNodes
+}
+
+var nodeCnt int32
+
+// nextNodeID hands out the next globally unique node name, shared by every
+// Renderer so diagrams stay consistent about what a "node" is.
+func nextNodeID() string {
+	return fmt.Sprintf("N%03d", atomic.AddInt32(&nodeCnt, 1))
+}
+
+// peekNextNodeID previews the name nextNodeID will return next, used to
+// wire an edge to a branch's entry node before that node has been emitted.
+func peekNextNodeID() string {
+	return fmt.Sprintf("N%03d", atomic.LoadInt32(&nodeCnt)+1)
+}
+
+// resetNodeCounter restarts node numbering from N001. Used between renders
+// in -watch mode so repeated re-renders of the same source produce the same
+// node names instead of growing unbounded.
+func resetNodeCounter() {
+	atomic.StoreInt32(&nodeCnt, 0)
+}
+
+// resolveCall looks up the entry/exit nodes for a called function. If the
+// function isn't declared in this file, it synthesizes the same
+// deterministic node names so the Call edges still point somewhere
+// recognizable instead of being silently dropped.
+func resolveCall(syms map[string]funcNodes, name string) funcNodes {
+	if nodes, ok := syms[name]; ok {
+		return nodes
+	}
+	return funcNodes{entry: "F_" + name + "_entry", exit: "F_" + name + "_exit"}
+}
+
+// prepLabel applies the format-independent part of label preparation
+// (literal-backtick unwrapping and word-wrapping of free text)
+// that every renderer needs before applying its own escaping/quoting rules.
+func prepLabel(s string) string {
+	if s == "" || len(s) < 2 {
+		return s
+	}
+	if !(strings.Contains(s, "\n") || strings.Contains(s, "<br>")) {
+		s = wordwrap.WrapString(s, 30)
+	}
+	if s[0] == '`' && s[len(s)-1] == '`' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+var (
+	watchFlag = flag.Bool("watch", false, "watch the input file and re-render on every change")
+	outFlag   = flag.String("out", "", "write rendered output to this path instead of stdout (used with -watch)")
+)
+
+// watchDebounce coalesces bursts of editor saves so a re-render doesn't
+// start while the file is still being written.
+const watchDebounce = 100 * time.Millisecond
+
+// watchRender renders fn once, then keeps re-rendering it to *outFlag (or
+// stdout) every time it changes until the process is interrupted.
+func watchRender(fn string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "watch")
+	}
+	defer watcher.Close()
+	if err = watcher.Add(fn); err != nil {
+		return errors.Wrapf(err, "watch %s", fn)
+	}
+
+	render := func() {
+		if err := renderFile(fn); err != nil {
+			log.Print(err)
+		}
+	}
+	render()
+
+	var debounce *time.Timer
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				// Many editors save by renaming a temp file over fn, which
+				// drops the inode being watched; re-arm on the new one.
+				watcher.Remove(fn)
+				if err := watcher.Add(fn); err != nil {
+					log.Print(errors.Wrapf(err, "re-watch %s", fn))
+					continue
+				}
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			if debounce == nil {
+				debounce = time.AfterFunc(watchDebounce, render)
+			} else {
+				debounce.Reset(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Print(err)
+		}
+	}
+}
+
+// renderFile reads fn and renders it to *outFlag, or stdout if unset.
+func renderFile(fn string) error {
+	src, err := os.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	w := io.Writer(os.Stdout)
+	if *outFlag != "" {
+		fh, err := os.Create(*outFlag)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+		w = fh
+	}
+	return renderSource(fn, string(src), w)
+}